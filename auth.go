@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// AuthSettings selects and configures the authentication mode applied
+// to "/", "/events", and any future admin endpoints.
+type AuthSettings struct {
+	Mode    string             `yaml:"mode,omitempty"` // "", "basic", "forward", "oidc"
+	Basic   *BasicAuthConfig   `yaml:"basic,omitempty"`
+	Forward *ForwardAuthConfig `yaml:"forward,omitempty"`
+	OIDC    *OIDCAuthConfig    `yaml:"oidc,omitempty"`
+}
+
+// BasicAuthConfig is an htpasswd-style user list for settings.auth.basic.
+type BasicAuthConfig struct {
+	Users []BasicAuthUser `yaml:"users"`
+}
+
+type BasicAuthUser struct {
+	Username     string   `yaml:"username"`
+	PasswordHash string   `yaml:"password_hash"` // bcrypt
+	Groups       []string `yaml:"groups,omitempty"`
+}
+
+// ForwardAuthConfig delegates authentication to an upstream reverse
+// proxy (Authelia, traefik-forward-auth, ...) that sets a trusted
+// header once it has verified the user.
+type ForwardAuthConfig struct {
+	UserHeader   string `yaml:"user_header,omitempty"`   // default: X-Forwarded-User
+	GroupsHeader string `yaml:"groups_header,omitempty"` // default: X-Forwarded-Groups
+	VerifyURL    string `yaml:"verify_url,omitempty"`    // optional upstream check before trusting headers
+
+	// TrustedProxies restricts which source IPs (CIDRs) are allowed to
+	// set UserHeader/GroupsHeader at all. SharedSecret additionally (or
+	// instead) requires the proxy to present a secret in SharedSecretHeader.
+	// At least one of the two must be set, or requests could forge the
+	// user header directly and impersonate anyone.
+	TrustedProxies     []string `yaml:"trusted_proxies,omitempty"`
+	SharedSecret       string   `yaml:"shared_secret,omitempty"`
+	SharedSecretHeader string   `yaml:"shared_secret_header,omitempty"` // default: X-Forward-Auth-Secret
+}
+
+// OIDCAuthConfig runs Authorization Code + PKCE against any OIDC issuer,
+// storing the resulting session in a signed cookie.
+type OIDCAuthConfig struct {
+	IssuerURL    string   `yaml:"issuer_url"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	GroupsClaim  string   `yaml:"groups_claim,omitempty"` // default: groups
+	CookieSecret string   `yaml:"cookie_secret"`          // HMAC key signing the session cookie
+}
+
+// authUser is the authenticated identity attached to the request
+// context, used by handleIndex to filter services/bookmarks by ACL.
+type authUser struct {
+	Username string
+	Groups   []string
+}
+
+type authUserContextKey struct{}
+
+// userFromContext returns the authenticated user for the request, or
+// nil when auth is disabled.
+func userFromContext(ctx context.Context) *authUser {
+	user, _ := ctx.Value(authUserContextKey{}).(*authUser)
+	return user
+}
+
+// newAuthMiddleware builds the middleware for settings.auth, wrapping
+// mux with whichever mode is configured. When Mode is empty it returns
+// mux unchanged (no auth, the historical default).
+func newAuthMiddleware(settings AuthSettings, mux http.Handler) (http.Handler, error) {
+	switch settings.Mode {
+	case "", "none":
+		return mux, nil
+	case "basic":
+		if settings.Basic == nil {
+			return nil, fmt.Errorf("auth mode is basic but settings.auth.basic is not configured")
+		}
+		return basicAuthMiddleware(*settings.Basic, mux), nil
+	case "forward":
+		if settings.Forward == nil {
+			return nil, fmt.Errorf("auth mode is forward but settings.auth.forward is not configured")
+		}
+		if len(settings.Forward.TrustedProxies) == 0 && settings.Forward.SharedSecret == "" {
+			return nil, fmt.Errorf("auth mode is forward but neither trusted_proxies nor shared_secret is configured; " +
+				"without one, any client can set the forwarded-user header directly and impersonate anyone")
+		}
+		return forwardAuthMiddleware(*settings.Forward, mux)
+	case "oidc":
+		if settings.OIDC == nil {
+			return nil, fmt.Errorf("auth mode is oidc but settings.auth.oidc is not configured")
+		}
+		return newOIDCAuthMiddleware(*settings.OIDC, mux)
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", settings.Mode)
+	}
+}
+
+func basicAuthMiddleware(cfg BasicAuthConfig, next http.Handler) http.Handler {
+	users := make(map[string]BasicAuthUser, len(cfg.Users))
+	for _, u := range cfg.Users {
+		users[u.Username] = u
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="homepage"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		u, exists := users[username]
+		if !exists || bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="homepage"`)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), authUserContextKey{}, &authUser{Username: username, Groups: u.Groups})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func forwardAuthMiddleware(cfg ForwardAuthConfig, next http.Handler) (http.Handler, error) {
+	userHeader := cfg.UserHeader
+	if userHeader == "" {
+		userHeader = "X-Forwarded-User"
+	}
+	groupsHeader := cfg.GroupsHeader
+	if groupsHeader == "" {
+		groupsHeader = "X-Forwarded-Groups"
+	}
+	secretHeader := cfg.SharedSecretHeader
+	if secretHeader == "" {
+		secretHeader = "X-Forward-Auth-Secret"
+	}
+
+	trustedProxies, err := parseCIDRs(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("parsing trusted_proxies: %w", err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Refuse to trust the forwarded headers unless the request
+		// actually came from the proxy we expect: either its source IP
+		// is in the allowlist, or it presents the shared secret we
+		// configured it with. Without this, any client reaching the
+		// port directly could set X-Forwarded-User itself.
+		if len(trustedProxies) > 0 && !sourceIPTrusted(r, trustedProxies) {
+			http.Error(w, "request did not originate from a trusted proxy", http.StatusForbidden)
+			return
+		}
+		if cfg.SharedSecret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(cfg.SharedSecret)) != 1 {
+			http.Error(w, "missing or invalid forward-auth shared secret", http.StatusForbidden)
+			return
+		}
+
+		if cfg.VerifyURL != "" {
+			verifyReq, err := http.NewRequestWithContext(r.Context(), http.MethodGet, cfg.VerifyURL, nil)
+			if err != nil {
+				http.Error(w, "auth verification failed", http.StatusBadGateway)
+				return
+			}
+			verifyReq.Header.Set(userHeader, r.Header.Get(userHeader))
+
+			resp, err := http.DefaultClient.Do(verifyReq)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				if resp != nil {
+					resp.Body.Close()
+				}
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			resp.Body.Close()
+		}
+
+		username := r.Header.Get(userHeader)
+		if username == "" {
+			http.Error(w, "missing forward-auth user header", http.StatusUnauthorized)
+			return
+		}
+
+		var groups []string
+		if raw := r.Header.Get(groupsHeader); raw != "" {
+			groups = strings.Split(raw, ",")
+			for i := range groups {
+				groups[i] = strings.TrimSpace(groups[i])
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), authUserContextKey{}, &authUser{Username: username, Groups: groups})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}), nil
+}
+
+// parseCIDRs parses a list of CIDR strings (a bare IP is treated as a
+// /32 or /128), used to restrict which source IPs may set forward-auth
+// headers.
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, raw := range cidrs {
+		if !strings.Contains(raw, "/") {
+			if ip := net.ParseIP(raw); ip != nil && ip.To4() != nil {
+				raw += "/32"
+			} else {
+				raw += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", raw, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// sourceIPTrusted reports whether the request's source IP falls within
+// one of the trusted proxy CIDRs.
+func sourceIPTrusted(r *http.Request, trusted []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+const oidcSessionCookie = "homepage_session"
+
+// oidcAuth holds the provider/oauth2 config plus the routes needed for
+// the Authorization Code + PKCE dance.
+type oidcAuth struct {
+	cfg      OIDCAuthConfig
+	provider *oidc.Provider
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// minCookieSecretLen is the minimum byte length required for
+// OIDCAuthConfig.CookieSecret, since it's used directly as the HMAC key
+// signing session cookies.
+const minCookieSecretLen = 32
+
+func newOIDCAuthMiddleware(cfg OIDCAuthConfig, next http.Handler) (http.Handler, error) {
+	if len(cfg.CookieSecret) < minCookieSecretLen {
+		return nil, fmt.Errorf("settings.auth.oidc.cookie_secret must be at least %d bytes", minCookieSecretLen)
+	}
+
+	ctx := context.Background()
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("discovering oidc issuer: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+
+	a := &oidcAuth{
+		cfg:      cfg,
+		provider: provider,
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/login", a.handleLogin)
+	mux.HandleFunc("/auth/callback", a.handleCallback)
+	mux.Handle("/", a.requireSession(next))
+
+	return mux, nil
+}
+
+// requestIsSecure reports whether r arrived over TLS, either directly or
+// as relayed by a reverse proxy via X-Forwarded-Proto. Used to decide
+// whether auth cookies can safely be marked Secure without breaking a
+// plain-http deployment.
+func requestIsSecure(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func (a *oidcAuth) handleLogin(w http.ResponseWriter, r *http.Request) {
+	verifier := oauth2.GenerateVerifier()
+	state := randomString(16)
+	secure := requestIsSecure(r)
+
+	http.SetCookie(w, &http.Cookie{Name: "oidc_verifier", Value: verifier, Path: "/", HttpOnly: true, Secure: secure, SameSite: http.SameSiteLaxMode, MaxAge: 600})
+	http.SetCookie(w, &http.Cookie{Name: "oidc_state", Value: state, Path: "/", HttpOnly: true, Secure: secure, SameSite: http.SameSiteLaxMode, MaxAge: 600})
+
+	authURL := a.oauth2.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (a *oidcAuth) handleCallback(w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie("oidc_state")
+	if err != nil || r.URL.Query().Get("state") != stateCookie.Value {
+		http.Error(w, "invalid state", http.StatusBadRequest)
+		return
+	}
+	verifierCookie, err := r.Cookie("oidc_verifier")
+	if err != nil {
+		http.Error(w, "missing pkce verifier", http.StatusBadRequest)
+		return
+	}
+
+	token, err := a.oauth2.Exchange(r.Context(), r.URL.Query().Get("code"), oauth2.VerifierOption(verifierCookie.Value))
+	if err != nil {
+		http.Error(w, "token exchange failed", http.StatusBadGateway)
+		return
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		http.Error(w, "no id_token in response", http.StatusBadGateway)
+		return
+	}
+	idToken, err := a.verifier.Verify(r.Context(), rawIDToken)
+	if err != nil {
+		http.Error(w, "invalid id_token", http.StatusUnauthorized)
+		return
+	}
+
+	var claims struct {
+		Email string `json:"email"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		http.Error(w, "invalid id_token claims", http.StatusUnauthorized)
+		return
+	}
+
+	groupsClaim := a.cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	var rawClaims map[string]any
+	if err := idToken.Claims(&rawClaims); err != nil {
+		http.Error(w, "invalid id_token claims", http.StatusUnauthorized)
+		return
+	}
+	groups := stringsFromClaim(rawClaims[groupsClaim])
+
+	session, err := signSession(authUser{Username: claims.Email, Groups: groups}, a.cfg.CookieSecret)
+	if err != nil {
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oidcSessionCookie, Value: session, Path: "/", HttpOnly: true, Secure: requestIsSecure(r), SameSite: http.SameSiteLaxMode, MaxAge: 86400})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (a *oidcAuth) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(oidcSessionCookie)
+		if err != nil {
+			http.Redirect(w, r, "/auth/login", http.StatusFound)
+			return
+		}
+
+		user, err := verifySession(cookie.Value, a.cfg.CookieSecret)
+		if err != nil {
+			http.Redirect(w, r, "/auth/login", http.StatusFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), authUserContextKey{}, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// signedSession is the JSON payload stored (base64url-encoded, HMAC
+// signed) in the session cookie.
+type signedSession struct {
+	User   authUser `json:"user"`
+	Expiry int64    `json:"expiry"`
+}
+
+func signSession(user authUser, secret string) (string, error) {
+	payload := signedSession{User: user, Expiry: time.Now().Add(24 * time.Hour).Unix()}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	sig := mac.Sum(nil)
+
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func verifySession(cookie, secret string) (*authUser, error) {
+	parts := strings.SplitN(cookie, ".", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed session cookie")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, fmt.Errorf("session signature mismatch")
+	}
+
+	var payload signedSession
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() > payload.Expiry {
+		return nil, fmt.Errorf("session expired")
+	}
+
+	return &payload.User, nil
+}
+
+// stringsFromClaim normalizes an OIDC claim value into a []string. IdPs
+// vary in how they encode a multi-valued claim like groups: most use a
+// JSON array of strings, but some emit a single bare string for a user in
+// exactly one group. Anything else (missing claim, non-string elements)
+// yields no groups rather than an error, since a misconfigured groups
+// claim shouldn't block login.
+func stringsFromClaim(v any) []string {
+	switch t := v.(type) {
+	case []any:
+		var groups []string
+		for _, elem := range t {
+			if s, ok := elem.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	case string:
+		if t == "" {
+			return nil
+		}
+		return []string{t}
+	default:
+		return nil
+	}
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// allowed reports whether a user may see an item restricted to the
+// given allow-list. An empty allow-list means the item is public.
+func allowed(user *authUser, allow []string) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+	for _, group := range user.Groups {
+		for _, a := range allow {
+			if subtle.ConstantTimeCompare([]byte(group), []byte(a)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// sdListenFDsStart is the first file descriptor passed by systemd socket
+// activation (fd 0-2 are stdio).
+const sdListenFDsStart = 3
+
+// listen returns a net.Listener for addr. When the process was started
+// with socket activation (LISTEN_FDS set and LISTEN_PID matching this
+// process), it adopts the first passed file descriptor instead of
+// binding addr itself, mirroring sd_listen_fds(3). Otherwise it falls
+// back to a plain TCP listen on addr.
+func listen(addr string) (net.Listener, error) {
+	if ln, ok, err := listenFromSystemd(); ok {
+		return ln, err
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenFromSystemd adopts the socket passed via LISTEN_FDS, if any. The
+// bool return reports whether socket activation was requested at all,
+// so callers can fall back to a normal bind when it wasn't.
+func listenFromSystemd() (net.Listener, bool, error) {
+	pidStr := os.Getenv("LISTEN_PID")
+	fdsStr := os.Getenv("LISTEN_FDS")
+	if pidStr == "" || fdsStr == "" {
+		return nil, false, nil
+	}
+
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil || pid != os.Getpid() {
+		return nil, false, nil
+	}
+
+	fds, err := strconv.Atoi(fdsStr)
+	if err != nil || fds < 1 {
+		return nil, true, fmt.Errorf("invalid LISTEN_FDS value %q", fdsStr)
+	}
+
+	// Homepage Lite only ever listens on one socket; adopt the first
+	// passed descriptor.
+	file := os.NewFile(uintptr(sdListenFDsStart), "LISTEN_FD_3")
+	ln, err := net.FileListener(file)
+	if err != nil {
+		return nil, true, fmt.Errorf("adopting socket-activated listener: %w", err)
+	}
+
+	return ln, true, nil
+}
@@ -1,18 +1,19 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
-	"crypto/tls"
 	"encoding/hex"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"html/template"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -36,16 +37,14 @@ var configPath string
 
 // Templates and Config variables
 var (
-	templates    *template.Template
-	config       Config
-	configMutex  sync.RWMutex
-	sseClients   map[chan string]bool
-	sseClientsMu sync.Mutex
+	templates   *template.Template
+	config      Config
+	configMutex sync.RWMutex
 )
 
 // Service status store (updated by background goroutine)
 var (
-	serviceStatus    = make(map[string]string)
+	serviceStatus    = make(map[string]*ServiceHealth)
 	serviceStatusMux sync.RWMutex
 )
 
@@ -55,44 +54,55 @@ func getServiceID(url string) string {
 	return hex.EncodeToString(hash[:])[:8]
 }
 
-func init() {
-	sseClients = make(map[chan string]bool)
-}
-
 type Config struct {
-	Services  []ServiceGroup  `yaml:"services"`
-	Bookmarks []BookmarkGroup `yaml:"bookmarks"`
-	Settings  Settings        `yaml:"settings"`
+	Services  []ServiceGroup   `yaml:"services"`
+	Bookmarks []BookmarkGroup  `yaml:"bookmarks"`
+	Settings  Settings         `yaml:"settings"`
+	Providers []ProviderConfig `yaml:"providers,omitempty"`
 }
 
 type ServiceGroup struct {
 	Group string    `yaml:"group"`
 	Items []Service `yaml:"items"`
+	Allow []string  `yaml:"allow,omitempty"` // user groups allowed to see this group; empty = public
 }
 
 type Service struct {
-	Name        string `yaml:"name"`
-	URL         string `yaml:"url"`
-	Description string `yaml:"description"`
-	Icon        string `yaml:"icon"`
-	Status      string `yaml:"-"`
+	Name        string       `yaml:"name"`
+	URL         string       `yaml:"url"`
+	Description string       `yaml:"description"`
+	Icon        string       `yaml:"icon"`
+	Probe       *ProbeConfig `yaml:"probe,omitempty"`
+	Allow       []string     `yaml:"allow,omitempty"` // user groups allowed to see this item; empty = inherit the group's Allow
+	Status      string       `yaml:"-"`
+	Latency     int64        `yaml:"-"` // milliseconds
+	CertExpiry  time.Time    `yaml:"-"`
 }
 
 type BookmarkGroup struct {
 	Group string     `yaml:"group"`
 	Items []Bookmark `yaml:"items"`
+	Allow []string   `yaml:"allow,omitempty"` // user groups allowed to see this group; empty = public
 }
 
 type Bookmark struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
-	Abbr string `yaml:"abbr"`
+	Name  string   `yaml:"name"`
+	URL   string   `yaml:"url"`
+	Abbr  string   `yaml:"abbr"`
+	Allow []string `yaml:"allow,omitempty"` // user groups allowed to see this item; empty = inherit the group's Allow
 }
 
 type Settings struct {
-	Title     string `yaml:"title"`
-	Port      int    `yaml:"port"`       // Server port (default: 8080)
-	ShowTitle bool   `yaml:"show_title"` // Show title in header (default: true)
+	Title     string          `yaml:"title"`
+	Port      int             `yaml:"port"`       // Server port (default: 8080)
+	ShowTitle bool            `yaml:"show_title"` // Show title in header (default: true)
+	Metrics   MetricsSettings `yaml:"metrics"`
+	Auth      AuthSettings    `yaml:"auth,omitempty"`
+}
+
+// MetricsSettings controls the opt-in Prometheus /metrics endpoint.
+type MetricsSettings struct {
+	Enabled bool `yaml:"enabled"`
 }
 
 // SystemMetrics holds all system metrics collected for display
@@ -151,54 +161,22 @@ func loadConfig() error {
 		return fmt.Errorf("error parsing config file: %v", err)
 	}
 
+	promConfigReloads.Inc()
+	logger.Info("config loaded", "path", configPath, "services", len(config.Services), "bookmarks", len(config.Bookmarks))
+
 	// Notify all clients that config has changed
 	broadcastSSE(SSETypeReload, nil)
 
 	return nil
 }
 
-type SSEMessageType string
-
-const (
-	SSETypeReload  SSEMessageType = "reload"
-	SSETypeService SSEMessageType = "service"
-	SSETypeMetrics SSEMessageType = "metrics"
-)
-
-type SSEMessage struct {
-	Type SSEMessageType `json:"type"`
-	Data any            `json:"data,omitempty"`
-}
-
-func broadcastSSE(msgType SSEMessageType, data any) {
-	msg := SSEMessage{Type: msgType}
-
-	if msgType != SSETypeReload {
-		msg.Data = data
-	}
-
-	jsonMsg, _ := json.Marshal(msg)
-	message := string(jsonMsg)
-
-	sseClientsMu.Lock()
-	defer sseClientsMu.Unlock()
-
-	for client := range sseClients {
-		select {
-		case client <- message:
-		default:
-			// Client not ready, skip
-		}
-	}
-}
-
-func watchConfig() error {
+func watchConfig(ctx context.Context) error {
 	absPath, err := filepath.Abs(configPath)
 	if err != nil {
 		return fmt.Errorf("error getting absolute path: %v", err)
 	}
 
-	fmt.Printf("Starting config file watcher for: %s\n", absPath)
+	logger.Info("starting config file watcher", "path", absPath)
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -225,18 +203,20 @@ func watchConfig() error {
 				// Check if the event is for our config file
 				if filepath.Base(event.Name) == filepath.Base(absPath) &&
 					event.Op&fsnotify.Write == fsnotify.Write {
-					fmt.Printf("Config file modified: %s\n", event.Name)
+					logger.Debug("config file modified", "path", event.Name)
 					if err := loadConfig(); err != nil {
-						fmt.Printf("Error reloading config: %v\n", err)
+						logger.Error("error reloading config", "error", err)
 					} else {
-						fmt.Println("Config reloaded successfully")
+						logger.Info("config reloaded successfully")
 					}
 				}
 			case err, ok := <-watcher.Errors:
 				if !ok {
 					return
 				}
-				fmt.Printf("Watcher error: %v\n", err)
+				logger.Error("watcher error", "error", err)
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
@@ -244,151 +224,192 @@ func watchConfig() error {
 	return nil
 }
 
-func handleSSE(w http.ResponseWriter, r *http.Request) {
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	// Create a buffered channel for this client
-	messageChan := make(chan string, 100)
-
-	// Register this client
-	sseClientsMu.Lock()
-	sseClients[messageChan] = true
-	sseClientsMu.Unlock()
-
-	// Send current metrics to the new client immediately
-	if metrics, err := collectSystemMetrics(); err == nil {
-		msg := SSEMessage{Type: SSETypeMetrics, Data: metrics}
-		jsonMsg, _ := json.Marshal(msg)
-		select {
-		case messageChan <- string(jsonMsg):
-		default:
-			// Client not ready, skip
-		}
-	}
-
-	// Clean up when the client disconnects
-	defer func() {
-		sseClientsMu.Lock()
-		delete(sseClients, messageChan)
-		sseClientsMu.Unlock()
-		close(messageChan)
-	}()
+// getServiceHealth returns the current health from the background-updated store.
+func getServiceHealth(url string) ServiceHealth {
+	serviceStatusMux.RLock()
+	defer serviceStatusMux.RUnlock()
 
-	// Keep the connection alive
-	for {
-		select {
-		case msg := <-messageChan:
-			fmt.Fprintf(w, "data: %s\n\n", msg)
-			w.(http.Flusher).Flush()
-		case <-r.Context().Done():
-			return
-		}
+	if health, exists := serviceStatus[url]; exists {
+		return *health
 	}
+	return ServiceHealth{Status: "checking"}
 }
 
-// checkServiceStatus performs a HEAD request to check if a service is responding.
-func checkServiceStatus(url string) string {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{
-		Transport: tr,
-		Timeout:   5 * time.Second,
-	}
+// defaultProbeInterval is used for services that don't set probe.interval.
+const defaultProbeInterval = 30 * time.Second
 
-	resp, err := client.Head(url)
-	// don't forget to close
-	if resp != nil {
-		defer resp.Body.Close()
-	}
-	if err != nil {
-		return "down"
-	}
-	return "up"
-}
+// probeScanInterval is the scheduling resolution at which
+// updateServiceStatusLoop checks which services are due for a probe. It is
+// deliberately finer than defaultProbeInterval so a short per-service
+// probe.interval is actually honored rather than rounded up to it.
+const probeScanInterval = 5 * time.Second
 
-// getServiceStatus returns the current status from the background-updated store
-func getServiceStatus(url string) string {
-	serviceStatusMux.RLock()
-	defer serviceStatusMux.RUnlock()
-
-	if status, exists := serviceStatus[url]; exists {
-		return status
-	}
-	return "checking"
-}
+// serviceNextProbe tracks, per service URL, when that service is next due
+// to be probed so each one can run on its own probe.interval instead of a
+// single fixed cadence for every service.
+var (
+	serviceNextProbeMu sync.Mutex
+	serviceNextProbe   = make(map[string]time.Time)
+)
 
-// updateServiceStatusLoop runs in background and updates all service statuses periodically
-func updateServiceStatusLoop() {
-	ticker := time.NewTicker(30 * time.Second)
+// updateServiceStatusLoop runs in background and updates service statuses
+// on each one's own probe interval.
+func updateServiceStatusLoop(ctx context.Context) {
+	ticker := time.NewTicker(probeScanInterval)
 	defer ticker.Stop()
 
 	// Initial check
 	updateAllServiceStatus()
 
-	for range ticker.C {
-		updateAllServiceStatus()
+	for {
+		select {
+		case <-ticker.C:
+			updateAllServiceStatus()
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
-// updateAllServiceStatus checks all services in parallel and updates the status map
+// updateAllServiceStatus probes every service that is currently due (per
+// its own probe.interval, or defaultProbeInterval if unset) in parallel and
+// updates the status map.
 func updateAllServiceStatus() {
 	configMutex.RLock()
 	services := config.Services
 	configMutex.RUnlock()
 
+	now := time.Now()
 	var wg sync.WaitGroup
-	tempStatus := make(map[string]string)
-	var mu sync.Mutex
+	type probed struct {
+		url        string
+		result     ProbeResult
+		groupAllow []string
+		itemAllow  []string
+	}
+	results := make(chan probed, len(services)*4)
 
-	// Check all services in parallel
+	// Probe every due service in parallel
 	for _, group := range services {
 		for _, service := range group.Items {
+			interval := defaultProbeInterval
+			if service.Probe != nil && service.Probe.Interval > 0 {
+				interval = service.Probe.Interval
+			}
+
+			serviceNextProbeMu.Lock()
+			due := now.After(serviceNextProbe[service.URL])
+			if due {
+				serviceNextProbe[service.URL] = now.Add(interval)
+			}
+			serviceNextProbeMu.Unlock()
+
+			if !due {
+				continue
+			}
+
 			wg.Add(1)
-			go func(url string) {
+			go func(service Service, groupAllow []string) {
 				defer wg.Done()
-				status := checkServiceStatus(url)
-
-				mu.Lock()
-				tempStatus[url] = status
-				mu.Unlock()
-			}(service.URL)
+				prober := newProber(service.Probe)
+				results <- probed{
+					url:        service.URL,
+					result:     prober.Probe(service.URL),
+					groupAllow: groupAllow,
+					itemAllow:  service.Allow,
+				}
+			}(service, group.Allow)
 		}
 	}
 
-	wg.Wait()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Drain probe results into a local slice without holding
+	// serviceStatusMux, since a probe can take up to its own timeout and
+	// every handleIndex render / Prometheus scrape would otherwise stall
+	// on serviceStatusMux.RLock() for the whole cycle.
+	updates := make([]probed, 0, len(services))
+	for p := range results {
+		updates = append(updates, p)
+	}
+
+	type change struct {
+		data       map[string]any
+		groupAllow []string
+		itemAllow  []string
+	}
+	var changes []change
 
-	// Broadcast status updates via SSE (only for changed services)
 	serviceStatusMux.Lock()
-	for url, newStatus := range tempStatus {
-		if oldStatus, exists := serviceStatus[url]; !exists || oldStatus != newStatus {
-			serviceID := getServiceID(url)
-			serviceData := map[string]string{"id": serviceID, "status": newStatus}
-			broadcastSSE(SSETypeService, serviceData)
+	for _, p := range updates {
+		promProbeLatency.Observe(p.result.Latency.Seconds())
+		if !p.result.Up {
+			promProbeFailures.Inc()
+		}
+
+		health, exists := serviceStatus[p.url]
+		if !exists {
+			health = &ServiceHealth{}
+			serviceStatus[p.url] = health
+		}
+		prevStatus := health.Status
+		health.record(p.result)
+
+		if !exists || prevStatus != health.Status {
+			serviceID := getServiceID(p.url)
+			changes = append(changes, change{
+				data: map[string]any{
+					"id":          serviceID,
+					"status":      health.Status,
+					"latency_ms":  health.Latency,
+					"message":     health.Message,
+					"cert_expiry": health.CertExpiry,
+				},
+				groupAllow: p.groupAllow,
+				itemAllow:  p.itemAllow,
+			})
 		}
 	}
-	// Update global status map in one go
-	serviceStatus = tempStatus
 	serviceStatusMux.Unlock()
+
+	// Broadcast status updates via SSE (only for changed services), once
+	// the lock protecting the shared status map has already been released.
+	// broadcastServiceStatus applies the same per-group/per-item ACL as
+	// handleIndex so a restricted service's status (and its raw probe
+	// error, which often contains the internal host:port) doesn't leak to
+	// every connected client regardless of group.
+	for _, c := range changes {
+		broadcastServiceStatus(c.data, c.groupAllow, c.itemAllow)
+	}
 }
 
 // updateMetricsLoop runs in background and broadcasts metrics updates periodically
-func updateMetricsLoop() {
+func updateMetricsLoop(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		metrics, err := collectSystemMetrics()
-		if err != nil {
-			continue
-		}
+	for {
+		select {
+		case <-ticker.C:
+			metrics, err := collectSystemMetrics()
+			if err != nil {
+				continue
+			}
+
+			promCPUUsage.Set(metrics.CPULoad)
+			promMemoryUsedBytes.Set(metrics.MemoryUsed * 1024 * 1024 * 1024)
+			promMemoryTotalBytes.Set(metrics.MemoryTotal * 1024 * 1024 * 1024)
+			promDiskUsedBytes.Set(metrics.DiskUsed * 1024 * 1024 * 1024)
+			promDiskTotalBytes.Set(metrics.DiskTotal * 1024 * 1024 * 1024)
 
-		// Broadcast metrics update via SSE
-		broadcastSSE(SSETypeMetrics, metrics)
+			// Broadcast metrics update via SSE
+			broadcastSSE(SSETypeMetrics, metrics)
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
@@ -459,21 +480,56 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	user := userFromContext(r.Context())
+
 	configMutex.RLock()
 
-	// Get status for all services from background store
-	for i := range config.Services {
-		for j := range config.Services[i].Items {
-			service := &config.Services[i].Items[j]
-			service.Status = getServiceStatus(service.URL)
+	var services []ServiceGroup
+	for _, group := range config.Services {
+		if !allowed(user, group.Allow) {
+			continue
+		}
+		var items []Service
+		for i := range group.Items {
+			service := group.Items[i]
+			if len(service.Allow) > 0 && !allowed(user, service.Allow) {
+				continue
+			}
+			health := getServiceHealth(service.URL)
+			service.Status = health.Status
+			service.Latency = health.Latency
+			service.CertExpiry = health.CertExpiry
+			items = append(items, service)
 		}
+		group.Items = items
+		services = append(services, group)
 	}
 
+	var bookmarks []BookmarkGroup
+	for _, group := range config.Bookmarks {
+		if !allowed(user, group.Allow) {
+			continue
+		}
+		var items []Bookmark
+		for _, bookmark := range group.Items {
+			if len(bookmark.Allow) > 0 && !allowed(user, bookmark.Allow) {
+				continue
+			}
+			items = append(items, bookmark)
+		}
+		group.Items = items
+		bookmarks = append(bookmarks, group)
+	}
+
+	visibleConfig := config
+	visibleConfig.Services = services
+	visibleConfig.Bookmarks = bookmarks
+
 	data := struct {
 		Config  Config
 		Version string
 	}{
-		Config:  config,
+		Config:  visibleConfig,
 		Version: Version,
 	}
 	configMutex.RUnlock()
@@ -487,38 +543,80 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 
 func main() {
 	// Parse command line flags
+	var logLevel, logFormat string
 	flag.StringVar(&configPath, "config", "config.yaml", "Path to configuration file")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn, error")
+	flag.StringVar(&logFormat, "log-format", "text", "Log format: text, json")
 	flag.Parse()
 
-	// Print version information
-	fmt.Printf("Homepage Lite %s\n", Version)
-	fmt.Printf("  Build Time: %s\n", BuildTime)
-	fmt.Printf("  Git Commit: %s\n", GitCommit)
-	fmt.Printf("  Go Version: %s\n", GoVersion)
-	fmt.Printf("  Config: %s\n", configPath)
-	fmt.Println()
+	logger = newLogger(logLevel, logFormat)
+
+	logger.Info("homepage lite starting",
+		"version", Version,
+		"build_time", BuildTime,
+		"git_commit", GitCommit,
+		"go_version", GoVersion,
+		"config", configPath,
+	)
 
 	if err := loadConfig(); err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
+		logger.Error("error loading config", "error", err)
 		return
 	}
 
 	if err := loadTemplates(); err != nil {
-		fmt.Printf("Error loading templates: %v\n", err)
+		logger.Error("error loading templates", "error", err)
 		return
 	}
 
-	if err := watchConfig(); err != nil {
-		fmt.Printf("Error setting up config watcher: %v\n", err)
-		return
-	}
+	// ctx is canceled on SIGINT/SIGTERM and propagated to every
+	// background loop and SSE connection so shutdown is graceful.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Start background goroutines
-	go updateServiceStatusLoop()
-	go updateMetricsLoop()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		updateServiceStatusLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		updateMetricsLoop(ctx)
+	}()
+
+	if len(config.Providers) > 0 {
+		// The providers subsystem owns config.Services/config.Bookmarks
+		// from here on; it always includes a file provider for the base
+		// config so its hot-reload replaces the legacy watcher instead
+		// of racing it for the same fields. watchConfig's full-struct
+		// reload would otherwise stomp on provider-contributed services
+		// on every base-file edit.
+		providers := buildProviders(config.Providers)
+		if !hasFileProvider(config.Providers) {
+			providers = append(providers, newFileProvider(configPath))
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runProviders(ctx, providers)
+		}()
+	} else if err := watchConfig(ctx); err != nil {
+		logger.Error("error setting up config watcher", "error", err)
+		return
+	}
 
 	// API routes
-	http.HandleFunc("/events", handleSSE)
+	http.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		handleSSE(ctx, w, r)
+	})
+
+	if config.Settings.Metrics.Enabled {
+		registerPrometheusCollectors()
+		http.Handle("/metrics", metricsHandler())
+		logger.Info("prometheus metrics enabled", "path", "/metrics")
+	}
 
 	// Static files - setup based on build mode
 	setupStaticFiles()
@@ -533,9 +631,40 @@ func main() {
 	}
 
 	addr := fmt.Sprintf(":%d", port)
-	fmt.Printf("Starting server on %s\n", addr)
-	err := http.ListenAndServe(addr, nil)
+	ln, err := listen(addr)
 	if err != nil {
-		fmt.Printf("Server error: %v\n", err)
+		logger.Error("error binding listener", "error", err)
+		return
 	}
+
+	authedMux, err := newAuthMiddleware(config.Settings.Auth, http.DefaultServeMux)
+	if err != nil {
+		logger.Error("error configuring auth", "error", err)
+		return
+	}
+
+	srv := &http.Server{Handler: loggingMiddleware(authedMux)}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("starting server", "addr", ln.Addr().String())
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("server error", "error", err)
+		}
+	case <-ctx.Done():
+		logger.Info("shutdown signal received, draining connections")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("error during server shutdown", "error", err)
+		}
+	}
+
+	wg.Wait()
+	logger.Info("shutdown complete")
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus collectors. Registered unconditionally; the /metrics route
+// itself is only mounted when settings.metrics.enabled is true.
+var (
+	promCPUUsage = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "homepage_cpu_usage_percent",
+		Help: "Current CPU usage percentage.",
+	})
+	promMemoryUsedBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "homepage_memory_used_bytes",
+		Help: "Memory currently in use, in bytes.",
+	})
+	promMemoryTotalBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "homepage_memory_total_bytes",
+		Help: "Total memory available, in bytes.",
+	})
+	promDiskUsedBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "homepage_disk_used_bytes",
+		Help: "Disk space currently in use on the root partition, in bytes.",
+	})
+	promDiskTotalBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "homepage_disk_total_bytes",
+		Help: "Total disk space on the root partition, in bytes.",
+	})
+
+	promProbeLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "homepage_probe_duration_seconds",
+		Help:    "Duration of service health probes.",
+		Buckets: prometheus.DefBuckets,
+	})
+	promProbeFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "homepage_probe_failures_total",
+		Help: "Total number of failed service health probes.",
+	})
+
+	promSSEClients = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "homepage_sse_clients",
+		Help: "Number of currently connected SSE clients.",
+	}, func() float64 { return float64(sseClientCount()) })
+	promConfigReloads = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "homepage_config_reloads_total",
+		Help: "Total number of config reloads.",
+	})
+)
+
+// serviceUpCollector exports homepage_service_up{service,group,url} by
+// reading the live config and service status store on every scrape.
+type serviceUpCollector struct {
+	desc *prometheus.Desc
+}
+
+func newServiceUpCollector() *serviceUpCollector {
+	return &serviceUpCollector{
+		desc: prometheus.NewDesc(
+			"homepage_service_up",
+			"Whether a service is currently up (1), degraded (0.5), or down (0).",
+			[]string{"service", "group", "url"},
+			nil,
+		),
+	}
+}
+
+func (c *serviceUpCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *serviceUpCollector) Collect(ch chan<- prometheus.Metric) {
+	configMutex.RLock()
+	groups := config.Services
+	configMutex.RUnlock()
+
+	for _, group := range groups {
+		for _, service := range group.Items {
+			health := getServiceHealth(service.URL)
+
+			var value float64
+			switch health.Status {
+			case "up":
+				value = 1
+			case "degraded":
+				value = 0.5
+			default:
+				value = 0
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, value,
+				service.Name, group.Group, service.URL)
+		}
+	}
+}
+
+// registerPrometheusCollectors wires the dynamic collectors into the
+// default Prometheus registry. Called once from main when metrics are
+// enabled.
+func registerPrometheusCollectors() {
+	prometheus.MustRegister(newServiceUpCollector())
+}
+
+// metricsHandler returns the promhttp handler for the /metrics route.
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historySize is the number of past probe results kept per service to
+// derive the degraded tri-state.
+const historySize = 5
+
+// ProbeConfig describes how a service should be health-checked. It is
+// optional on a Service; when omitted the legacy HTTPS HEAD behavior is
+// used.
+type ProbeConfig struct {
+	Type         string            `yaml:"type"` // http, tcp, tls, ping
+	Path         string            `yaml:"path,omitempty"`
+	Method       string            `yaml:"method,omitempty"`
+	ExpectStatus []int             `yaml:"expect_status,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty"`
+	Interval     time.Duration     `yaml:"interval,omitempty"`
+	Timeout      time.Duration     `yaml:"timeout,omitempty"`
+}
+
+// ProbeResult is the outcome of a single probe attempt.
+type ProbeResult struct {
+	Up         bool
+	Latency    time.Duration
+	CertExpiry time.Time // zero value means not applicable
+	Err        error
+}
+
+// Prober checks the health of a single service.
+type Prober interface {
+	Probe(url string) ProbeResult
+}
+
+// newProber builds the Prober for a service's probe configuration,
+// falling back to the legacy HTTPS HEAD prober when none is set.
+func newProber(cfg *ProbeConfig) Prober {
+	if cfg == nil {
+		return httpProber{}
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	switch cfg.Type {
+	case "tcp":
+		return tcpProber{timeout: timeout}
+	case "tls":
+		return tlsProber{timeout: timeout}
+	case "ping":
+		return pingProber{timeout: timeout}
+	case "http":
+		// An explicit http probe opts into strict status checking, since
+		// the user is presumably configuring it because they care about
+		// a specific response.
+		method := cfg.Method
+		if method == "" {
+			method = http.MethodHead
+		}
+		expect := cfg.ExpectStatus
+		if len(expect) == 0 {
+			expect = []int{200}
+		}
+		return httpProber{
+			path:    cfg.Path,
+			method:  method,
+			expect:  expect,
+			headers: cfg.Headers,
+			timeout: timeout,
+		}
+	case "":
+		// No probe.type set: keep the legacy "any HTTP response means
+		// reachable" behavior (a redirect, login wall, or 5xx still means
+		// the service answered) so upgrading to this field is a no-op for
+		// existing configs. Only a transport-level error counts as down.
+		method := cfg.Method
+		if method == "" {
+			method = http.MethodHead
+		}
+		return httpProber{
+			path:    cfg.Path,
+			method:  method,
+			expect:  cfg.ExpectStatus,
+			headers: cfg.Headers,
+			timeout: timeout,
+		}
+	default:
+		return httpProber{}
+	}
+}
+
+// httpProber performs an HTTP request and checks the response status
+// against the expected set.
+type httpProber struct {
+	path    string
+	method  string
+	expect  []int
+	headers map[string]string
+	timeout time.Duration
+}
+
+func (p httpProber) Probe(url string) ProbeResult {
+	method := p.method
+	if method == "" {
+		method = http.MethodHead
+	}
+	timeout := p.timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	target := url
+	if p.path != "" {
+		target = strings.TrimSuffix(url, "/") + "/" + strings.TrimPrefix(p.path, "/")
+	}
+
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	client := &http.Client{Transport: tr, Timeout: timeout}
+
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return ProbeResult{Err: err}
+	}
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return ProbeResult{Latency: latency, Err: err}
+	}
+
+	var certExpiry time.Time
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		certExpiry = resp.TLS.PeerCertificates[0].NotAfter
+	}
+
+	if len(p.expect) == 0 {
+		// No expect_status configured: any response at all (including
+		// redirects, auth walls, 5xx) means the service is reachable.
+		return ProbeResult{Up: true, Latency: latency, CertExpiry: certExpiry}
+	}
+
+	for _, status := range p.expect {
+		if resp.StatusCode == status {
+			return ProbeResult{Up: true, Latency: latency, CertExpiry: certExpiry}
+		}
+	}
+	return ProbeResult{
+		Latency:    latency,
+		CertExpiry: certExpiry,
+		Err:        fmt.Errorf("unexpected status %d", resp.StatusCode),
+	}
+}
+
+// tcpProber dials the host:port parsed out of the service URL.
+type tcpProber struct {
+	timeout time.Duration
+}
+
+func (p tcpProber) Probe(url string) ProbeResult {
+	host := hostPort(url)
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", host, p.timeout)
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Latency: latency, Err: err}
+	}
+	conn.Close()
+	return ProbeResult{Up: true, Latency: latency}
+}
+
+// tlsProber opens a TLS connection and reports certificate expiry
+// alongside reachability.
+type tlsProber struct {
+	timeout time.Duration
+}
+
+func (p tlsProber) Probe(url string) ProbeResult {
+	host := hostPort(url)
+	dialer := &net.Dialer{Timeout: p.timeout}
+
+	start := time.Now()
+	conn, err := tls.DialWithDialer(dialer, "tcp", host, &tls.Config{InsecureSkipVerify: true})
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Latency: latency, Err: err}
+	}
+	defer conn.Close()
+
+	var certExpiry time.Time
+	if certs := conn.ConnectionState().PeerCertificates; len(certs) > 0 {
+		certExpiry = certs[0].NotAfter
+	}
+	return ProbeResult{Up: true, Latency: latency, CertExpiry: certExpiry}
+}
+
+// pingProber shells out to the system `ping` binary for ICMP-only
+// devices that don't expose any TCP port.
+type pingProber struct {
+	timeout time.Duration
+}
+
+func (p pingProber) Probe(url string) ProbeResult {
+	host := strings.Split(hostPort(url), ":")[0]
+	seconds := int(p.timeout.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	start := time.Now()
+	cmd := exec.Command("ping", "-c", "1", "-W", strconv.Itoa(seconds), host)
+	err := cmd.Run()
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Latency: latency, Err: err}
+	}
+	return ProbeResult{Up: true, Latency: latency}
+}
+
+// hostPort strips the scheme and path from a service URL and ensures a
+// port is present, defaulting to 443/80 based on scheme.
+func hostPort(url string) string {
+	trimmed := url
+	scheme := "http"
+	if strings.HasPrefix(trimmed, "https://") {
+		scheme = "https"
+		trimmed = strings.TrimPrefix(trimmed, "https://")
+	} else {
+		trimmed = strings.TrimPrefix(trimmed, "http://")
+	}
+	if idx := strings.Index(trimmed, "/"); idx != -1 {
+		trimmed = trimmed[:idx]
+	}
+	if strings.Contains(trimmed, ":") {
+		return trimmed
+	}
+	if scheme == "https" {
+		return trimmed + ":443"
+	}
+	return trimmed + ":80"
+}
+
+// ServiceHealth is the cached, tri-state health of a service along with
+// the diagnostic detail the frontend renders (latency, cert expiry).
+type ServiceHealth struct {
+	Status     string    `json:"status"` // up, degraded, down
+	Latency    int64     `json:"latency_ms,omitempty"`
+	CertExpiry time.Time `json:"cert_expiry,omitempty"`
+	Message    string    `json:"message,omitempty"`
+
+	history []bool // ring of recent up/down results, most recent last
+}
+
+// record appends a result to the history (capped at historySize) and
+// derives the up/degraded/down tri-state from it.
+func (h *ServiceHealth) record(result ProbeResult) {
+	h.history = append(h.history, result.Up)
+	if len(h.history) > historySize {
+		h.history = h.history[len(h.history)-historySize:]
+	}
+
+	h.Latency = result.Latency.Milliseconds()
+	h.CertExpiry = result.CertExpiry
+	if result.Err != nil {
+		h.Message = result.Err.Error()
+	} else {
+		h.Message = ""
+	}
+
+	failures := 0
+	for _, up := range h.history {
+		if !up {
+			failures++
+		}
+	}
+
+	switch {
+	case failures == 0:
+		h.Status = "up"
+	case failures == len(h.history):
+		h.Status = "down"
+	default:
+		h.Status = "degraded"
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import "context"
+
+// ConfigDelta is the set of services/bookmarks contributed by a single
+// Provider. The merger keys deltas by Source so each provider's most
+// recent contribution can be replaced independently of the others.
+type ConfigDelta struct {
+	Source    string
+	Services  []ServiceGroup
+	Bookmarks []BookmarkGroup
+}
+
+// Provider discovers services and bookmarks from a backing system (a
+// YAML file, the Docker Engine, a Kubernetes cluster, ...) and streams
+// updates on the returned channel as they change. The channel is closed
+// when ctx is canceled.
+type Provider interface {
+	Name() string
+	Subscribe(ctx context.Context) <-chan ConfigDelta
+}
+
+// ProviderConfig configures one entry of the providers: block in the
+// YAML config.
+type ProviderConfig struct {
+	Type       string                    `yaml:"type"` // file, docker, kubernetes
+	Docker     *DockerProviderConfig     `yaml:"docker,omitempty"`
+	Kubernetes *KubernetesProviderConfig `yaml:"kubernetes,omitempty"`
+}
+
+// buildProviders instantiates a Provider for each configured entry,
+// skipping ones with an unknown type (logged, not fatal, since a typo in
+// one provider shouldn't take down the rest of the dashboard).
+func buildProviders(configs []ProviderConfig) []Provider {
+	var providers []Provider
+	for _, pc := range configs {
+		switch pc.Type {
+		case "docker":
+			providers = append(providers, newDockerProvider(pc.Docker))
+		case "kubernetes":
+			providers = append(providers, newKubernetesProvider(pc.Kubernetes))
+		case "file":
+			providers = append(providers, newFileProvider(configPath))
+		default:
+			logger.Warn("unknown provider type, skipping", "type", pc.Type)
+		}
+	}
+	return providers
+}
+
+// hasFileProvider reports whether the providers: block already includes
+// an explicit file provider for the base config.
+func hasFileProvider(configs []ProviderConfig) bool {
+	for _, pc := range configs {
+		if pc.Type == "file" {
+			return true
+		}
+	}
+	return false
+}
+
+// runProviders subscribes to every configured provider and merges their
+// deltas into the live config, broadcasting a reload to SSE clients on
+// every change. Callers always include a file provider for the base
+// config among providers, so it - not the legacy watchConfig loop -
+// owns config.Services/config.Bookmarks whenever providers are active.
+func runProviders(ctx context.Context, providers []Provider) {
+	merged := make(map[string]ConfigDelta)
+
+	deltas := make(chan ConfigDelta)
+	for _, p := range providers {
+		p := p
+		go func() {
+			for delta := range p.Subscribe(ctx) {
+				deltas <- delta
+			}
+		}()
+	}
+
+	for {
+		select {
+		case delta := <-deltas:
+			logger.Info("provider delta received", "source", delta.Source, "services", len(delta.Services), "bookmarks", len(delta.Bookmarks))
+			merged[delta.Source] = delta
+			applyMergedConfig(merged)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// applyMergedConfig composes config.Services/config.Bookmarks from the
+// union of all providers' most recent deltas and notifies clients.
+func applyMergedConfig(merged map[string]ConfigDelta) {
+	var services []ServiceGroup
+	var bookmarks []BookmarkGroup
+	for _, delta := range merged {
+		services = append(services, delta.Services...)
+		bookmarks = append(bookmarks, delta.Bookmarks...)
+	}
+
+	configMutex.Lock()
+	config.Services = services
+	config.Bookmarks = bookmarks
+	configMutex.Unlock()
+
+	broadcastSSE(SSETypeReload, nil)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// DockerProviderConfig configures the Docker provider.
+type DockerProviderConfig struct {
+	Host        string `yaml:"host,omitempty"` // e.g. unix:///var/run/docker.sock
+	LabelPrefix string `yaml:"label_prefix,omitempty"`
+}
+
+// dockerProvider generates Service entries from running container
+// labels (homepage.group, homepage.name, homepage.url, homepage.icon)
+// and re-scans whenever a container starts, stops, or dies.
+type dockerProvider struct {
+	host   string
+	prefix string
+}
+
+func newDockerProvider(cfg *DockerProviderConfig) *dockerProvider {
+	p := &dockerProvider{host: client.DefaultDockerHost, prefix: "homepage."}
+	if cfg != nil {
+		if cfg.Host != "" {
+			p.host = cfg.Host
+		}
+		if cfg.LabelPrefix != "" {
+			p.prefix = cfg.LabelPrefix
+		}
+	}
+	return p
+}
+
+func (p *dockerProvider) Name() string { return "docker:" + p.host }
+
+func (p *dockerProvider) Subscribe(ctx context.Context) <-chan ConfigDelta {
+	out := make(chan ConfigDelta)
+
+	go func() {
+		defer close(out)
+
+		cli, err := client.NewClientWithOpts(client.WithHost(p.host), client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			logger.Error("docker provider: connecting", "error", err)
+			return
+		}
+		defer cli.Close()
+
+		scan := func() {
+			delta, err := p.scan(ctx, cli)
+			if err != nil {
+				logger.Error("docker provider: scan failed", "error", err)
+				return
+			}
+			out <- delta
+		}
+
+		scan()
+
+		eventFilter := filters.NewArgs(filters.Arg("type", "container"))
+		msgs, errs := cli.Events(ctx, types.EventsOptions{Filters: eventFilter})
+
+		for {
+			select {
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				switch msg.Action {
+				case "start", "stop", "die", "destroy":
+					scan()
+				}
+			case err, ok := <-errs:
+				if !ok {
+					return
+				}
+				logger.Error("docker provider: event stream error", "error", err)
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// scan lists running containers and builds ServiceGroups keyed by the
+// homepage.group label.
+func (p *dockerProvider) scan(ctx context.Context, cli *client.Client) (ConfigDelta, error) {
+	containers, err := cli.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		return ConfigDelta{}, err
+	}
+
+	groups := make(map[string]*ServiceGroup)
+	for _, c := range containers {
+		name := c.Labels[p.prefix+"name"]
+		url := c.Labels[p.prefix+"url"]
+		if name == "" || url == "" {
+			continue
+		}
+		group := c.Labels[p.prefix+"group"]
+		if group == "" {
+			group = "Docker"
+		}
+
+		g, ok := groups[group]
+		if !ok {
+			g = &ServiceGroup{Group: group}
+			groups[group] = g
+		}
+		g.Items = append(g.Items, Service{
+			Name: name,
+			URL:  url,
+			Icon: c.Labels[p.prefix+"icon"],
+		})
+	}
+
+	var services []ServiceGroup
+	for _, g := range groups {
+		services = append(services, *g)
+	}
+
+	return ConfigDelta{Source: p.Name(), Services: services}, nil
+}
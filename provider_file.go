@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+)
+
+// fileProvider is the original YAML-file behavior refactored to satisfy
+// the Provider interface, so it can be composed with other providers
+// under the providers: block.
+type fileProvider struct {
+	path string
+}
+
+func newFileProvider(path string) *fileProvider {
+	return &fileProvider{path: path}
+}
+
+func (p *fileProvider) Name() string { return "file:" + p.path }
+
+func (p *fileProvider) Subscribe(ctx context.Context) <-chan ConfigDelta {
+	out := make(chan ConfigDelta)
+
+	go func() {
+		defer close(out)
+
+		if delta, err := p.read(); err == nil {
+			out <- delta
+		} else {
+			logger.Error("file provider: initial read failed", "path", p.path, "error", err)
+		}
+
+		absPath, err := filepath.Abs(p.path)
+		if err != nil {
+			logger.Error("file provider: resolving path", "error", err)
+			return
+		}
+
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			logger.Error("file provider: creating watcher", "error", err)
+			return
+		}
+		defer watcher.Close()
+
+		if err := watcher.Add(filepath.Dir(absPath)); err != nil {
+			logger.Error("file provider: watching directory", "error", err)
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) == filepath.Base(absPath) &&
+					event.Op&fsnotify.Write == fsnotify.Write {
+					if delta, err := p.read(); err == nil {
+						out <- delta
+					} else {
+						logger.Error("file provider: reload failed", "error", err)
+					}
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("file provider: watcher error", "error", err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func (p *fileProvider) read() (ConfigDelta, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return ConfigDelta{}, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return ConfigDelta{}, err
+	}
+
+	return ConfigDelta{Source: p.Name(), Services: cfg.Services, Bookmarks: cfg.Bookmarks}, nil
+}
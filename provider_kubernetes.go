@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubernetesProviderConfig configures the Kubernetes provider.
+type KubernetesProviderConfig struct {
+	Kubeconfig string `yaml:"kubeconfig,omitempty"` // empty uses $KUBECONFIG or ~/.kube/config, else in-cluster config
+	Namespace  string `yaml:"namespace,omitempty"`  // empty watches all namespaces
+}
+
+// kubernetesProvider generates a Service entry for every Ingress object
+// whose first rule has a host, taking name, group and icon from the
+// homepage.name/homepage.group/homepage.icon annotations when present,
+// and watches for changes so the dashboard stays in sync with the cluster.
+type kubernetesProvider struct {
+	kubeconfig string
+	namespace  string
+}
+
+func newKubernetesProvider(cfg *KubernetesProviderConfig) *kubernetesProvider {
+	p := &kubernetesProvider{}
+	if cfg != nil {
+		p.kubeconfig = cfg.Kubeconfig
+		p.namespace = cfg.Namespace
+	}
+	return p
+}
+
+func (p *kubernetesProvider) Name() string { return "kubernetes:" + p.namespace }
+
+func (p *kubernetesProvider) Subscribe(ctx context.Context) <-chan ConfigDelta {
+	out := make(chan ConfigDelta)
+
+	go func() {
+		defer close(out)
+
+		clientset, err := p.buildClientset()
+		if err != nil {
+			logger.Error("kubernetes provider: building client", "error", err)
+			return
+		}
+
+		watcher, err := clientset.NetworkingV1().Ingresses(p.namespace).Watch(ctx, metav1.ListOptions{})
+		if err != nil {
+			logger.Error("kubernetes provider: watching ingresses", "error", err)
+			return
+		}
+		defer watcher.Stop()
+
+		scan := func() {
+			delta, err := p.scan(ctx, clientset)
+			if err != nil {
+				logger.Error("kubernetes provider: scan failed", "error", err)
+				return
+			}
+			out <- delta
+		}
+
+		scan()
+
+		for {
+			select {
+			case _, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+				scan()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func (p *kubernetesProvider) buildClientset() (*kubernetes.Clientset, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if p.kubeconfig != "" {
+		loadingRules.ExplicitPath = p.kubeconfig
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// scan lists Ingress objects and builds ServiceGroups keyed by the
+// homepage.group annotation.
+func (p *kubernetesProvider) scan(ctx context.Context, clientset *kubernetes.Clientset) (ConfigDelta, error) {
+	ingresses, err := clientset.NetworkingV1().Ingresses(p.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return ConfigDelta{}, err
+	}
+
+	groups := make(map[string]*ServiceGroup)
+	for _, ing := range ingresses.Items {
+		name := ing.Annotations["homepage.name"]
+		if name == "" {
+			name = ing.Name
+		}
+		group := ing.Annotations["homepage.group"]
+		if group == "" {
+			group = "Kubernetes"
+		}
+
+		url := ingressURL(ing)
+		if url == "" {
+			continue
+		}
+
+		g, ok := groups[group]
+		if !ok {
+			g = &ServiceGroup{Group: group}
+			groups[group] = g
+		}
+		g.Items = append(g.Items, Service{
+			Name: name,
+			URL:  url,
+			Icon: ing.Annotations["homepage.icon"],
+		})
+	}
+
+	var services []ServiceGroup
+	for _, g := range groups {
+		services = append(services, *g)
+	}
+
+	return ConfigDelta{Source: p.Name(), Services: services}, nil
+}
+
+// ingressURL derives a service URL from the first rule's host, assuming
+// TLS when a matching entry exists under spec.tls.
+func ingressURL(ing networkingv1.Ingress) string {
+	if len(ing.Spec.Rules) == 0 {
+		return ""
+	}
+	host := ing.Spec.Rules[0].Host
+	if host == "" {
+		return ""
+	}
+
+	scheme := "http"
+	for _, tls := range ing.Spec.TLS {
+		for _, h := range tls.Hosts {
+			if h == host {
+				scheme = "https"
+			}
+		}
+	}
+	return scheme + "://" + host
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sseHistorySize is how many past events are kept so a reconnecting
+// client can replay what it missed via Last-Event-ID.
+const sseHistorySize = 256
+
+// sseClientBuffer is the size of a client's outgoing message queue for
+// non-metrics events.
+const sseClientBuffer = 100
+
+// sseFullEvictAfter is how long a client's queue may stay full before
+// it is disconnected instead of having further messages silently
+// dropped.
+const sseFullEvictAfter = 10 * time.Second
+
+// sseHeartbeatInterval keeps intermediaries (proxies, load balancers)
+// from treating an idle SSE connection as dead.
+const sseHeartbeatInterval = 15 * time.Second
+
+type SSEMessageType string
+
+const (
+	SSETypeReload  SSEMessageType = "reload"
+	SSETypeService SSEMessageType = "service"
+	SSETypeMetrics SSEMessageType = "metrics"
+	SSETypeBye     SSEMessageType = "bye"
+)
+
+type SSEMessage struct {
+	Type SSEMessageType `json:"type"`
+	Data any            `json:"data,omitempty"`
+}
+
+// sseEvent is a broadcast message with the monotonic ID used for
+// Last-Event-ID replay. groupAllow/itemAllow mirror the ACL handleIndex
+// applies to the rendered page, so a service-status event restricted to
+// a group never reaches a client outside it - both live and on replay.
+// Both are nil for events with no item behind them (reload, metrics, bye).
+type sseEvent struct {
+	id         uint64
+	message    []byte
+	groupAllow []string
+	itemAllow  []string
+}
+
+// visibleTo reports whether user may receive this event, using the same
+// ACL rule as handleIndex: the group-level and item-level allow-lists
+// must each pass (an empty list is public).
+func (ev sseEvent) visibleTo(user *authUser) bool {
+	return allowed(user, ev.groupAllow) && allowed(user, ev.itemAllow)
+}
+
+var sseNextEventID uint64
+
+// sseClient is one connected SSE subscriber. Metrics updates go through
+// a single-slot channel so a burst of updates coalesces into the latest
+// value instead of queuing; everything else goes through a regular
+// buffered channel.
+type sseClient struct {
+	user *authUser // nil when auth is disabled
+
+	messages chan sseEvent
+	metrics  chan sseEvent
+	done     chan struct{}
+
+	fullSince time.Time // zero when the message queue isn't currently full
+}
+
+func newSSEClient(user *authUser) *sseClient {
+	return &sseClient{
+		user:     user,
+		messages: make(chan sseEvent, sseClientBuffer),
+		metrics:  make(chan sseEvent, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// sendMetrics replaces any pending metrics event with the latest one,
+// so a slow client never sees a backlog of stale readings.
+func (c *sseClient) sendMetrics(ev sseEvent) {
+	for {
+		select {
+		case c.metrics <- ev:
+			return
+		default:
+			select {
+			case <-c.metrics:
+			default:
+			}
+		}
+	}
+}
+
+var (
+	sseClients   = make(map[*sseClient]bool)
+	sseClientsMu sync.Mutex
+
+	sseHistory   [sseHistorySize]sseEvent // ring buffer, keyed by id % sseHistorySize
+	sseHistoryMu sync.Mutex
+)
+
+// recordSSEEvent appends an event to the ring buffer used for
+// Last-Event-ID resume.
+func recordSSEEvent(ev sseEvent) {
+	sseHistoryMu.Lock()
+	defer sseHistoryMu.Unlock()
+	sseHistory[ev.id%sseHistorySize] = ev
+}
+
+// eventsSince returns buffered events with id > lastID, oldest first.
+// Events older than the ring buffer's capacity are simply absent; the
+// caller then just resumes with live updates from here.
+func eventsSince(lastID uint64) []sseEvent {
+	sseHistoryMu.Lock()
+	defer sseHistoryMu.Unlock()
+
+	var events []sseEvent
+	for _, ev := range sseHistory {
+		if ev.id > lastID {
+			events = append(events, ev)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].id < events[j].id })
+	return events
+}
+
+// broadcastSSE sends a public event - one with no per-item ACL - to every
+// connected client.
+func broadcastSSE(msgType SSEMessageType, data any) {
+	broadcastSSEAllowed(msgType, data, nil, nil)
+}
+
+// broadcastServiceStatus sends a service-status event only to clients
+// whose authenticated user passes both the service's group-level and
+// item-level allow-list, the same check handleIndex applies when
+// rendering the page. Without this, any connected client could read the
+// status and raw probe error (which often contains the internal
+// host:port) of a service restricted to a different group.
+func broadcastServiceStatus(data any, groupAllow, itemAllow []string) {
+	broadcastSSEAllowed(SSETypeService, data, groupAllow, itemAllow)
+}
+
+func broadcastSSEAllowed(msgType SSEMessageType, data any, groupAllow, itemAllow []string) {
+	msg := SSEMessage{Type: msgType}
+
+	if msgType != SSETypeReload {
+		msg.Data = data
+	}
+
+	jsonMsg, _ := json.Marshal(msg)
+
+	ev := sseEvent{
+		id:         atomic.AddUint64(&sseNextEventID, 1),
+		message:    jsonMsg,
+		groupAllow: groupAllow,
+		itemAllow:  itemAllow,
+	}
+	recordSSEEvent(ev)
+
+	sseClientsMu.Lock()
+	defer sseClientsMu.Unlock()
+
+	var evicted []*sseClient
+
+	for client := range sseClients {
+		if !ev.visibleTo(client.user) {
+			continue
+		}
+
+		if msgType == SSETypeMetrics {
+			// Metrics never queue up, so they say nothing about whether
+			// the message queue is full; leave fullSince as it is.
+			client.sendMetrics(ev)
+			continue
+		}
+
+		var ok bool
+		select {
+		case client.messages <- ev:
+			ok = true
+		default:
+			ok = false
+		}
+
+		if ok {
+			client.fullSince = time.Time{}
+			continue
+		}
+
+		if client.fullSince.IsZero() {
+			client.fullSince = time.Now()
+		} else if time.Since(client.fullSince) > sseFullEvictAfter {
+			evicted = append(evicted, client)
+		}
+	}
+
+	for _, client := range evicted {
+		logger.Warn("evicting slow sse client", "full_since", client.fullSince)
+		delete(sseClients, client)
+		close(client.done)
+	}
+}
+
+// sseClientCount returns the number of currently connected SSE clients,
+// exposed to the Prometheus /metrics endpoint.
+func sseClientCount() int {
+	sseClientsMu.Lock()
+	defer sseClientsMu.Unlock()
+	return len(sseClients)
+}
+
+func handleSSE(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	// Set headers for SSE
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	client := newSSEClient(userFromContext(r.Context()))
+
+	sseClientsMu.Lock()
+	sseClients[client] = true
+	sseClientsMu.Unlock()
+
+	defer func() {
+		sseClientsMu.Lock()
+		delete(sseClients, client)
+		sseClientsMu.Unlock()
+	}()
+
+	flusher := w.(http.Flusher)
+
+	// Replay missed events for a reconnecting client.
+	if lastID, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		for _, ev := range eventsSince(lastID) {
+			if !ev.visibleTo(client.user) {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.id, ev.message)
+		}
+		flusher.Flush()
+	}
+
+	// Send current metrics to the new client immediately.
+	if metrics, err := collectSystemMetrics(); err == nil {
+		msg := SSEMessage{Type: SSETypeMetrics, Data: metrics}
+		jsonMsg, _ := json.Marshal(msg)
+		fmt.Fprintf(w, "data: %s\n\n", jsonMsg)
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case ev := <-client.messages:
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.id, ev.message)
+			flusher.Flush()
+		case ev := <-client.metrics:
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.id, ev.message)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case <-client.done:
+			// Evicted by the broadcaster for staying full too long.
+			return
+		case <-r.Context().Done():
+			return
+		case <-ctx.Done():
+			// Server is shutting down: let the client know so it can
+			// reconnect cleanly instead of seeing a dropped connection.
+			byeMsg, _ := json.Marshal(SSEMessage{Type: SSETypeBye})
+			fmt.Fprintf(w, "data: %s\n\n", byeMsg)
+			flusher.Flush()
+			return
+		}
+	}
+}